@@ -0,0 +1,328 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ResourceData is the interface providers use, from within their CRUD
+// callbacks, to read and write the attributes of a single resource
+// instance.
+type ResourceData struct {
+	schema schemaMap
+
+	state *terraform.InstanceState
+	diff  *terraform.InstanceDiff
+
+	attrs    map[string]string
+	meta     map[string]string
+	timeouts *ResourceTimeout
+
+	id    string
+	idSet bool
+}
+
+// Timeout returns the duration configured for the given operation (one of
+// TimeoutCreate, TimeoutRead, TimeoutUpdate or TimeoutDelete), so a provider
+// callback can bound its own retry/polling loop. If the resource declares no
+// Timeouts, the ones last persisted in state are used; it returns 0 if there
+// are none there either.
+func (d *ResourceData) Timeout(key string) time.Duration {
+	return d.timeouts.forKey(key)
+}
+
+// Id returns the resource's current ID, preferring a value set via SetId
+// during this operation over whatever was already in state.
+func (d *ResourceData) Id() string {
+	if d.idSet {
+		return d.id
+	}
+	if d.state != nil {
+		return d.state.ID
+	}
+	return ""
+}
+
+// SetId sets the resource's ID. Providers call this from Create to record
+// the ID of a newly created resource, and from Read/Delete with an empty
+// string to signal that the resource no longer exists.
+func (d *ResourceData) SetId(v string) {
+	d.id = v
+	d.idSet = true
+}
+
+// Get returns the current value of key, coerced to the type its Schema
+// declares. Keys with no value return that type's zero value; keys not in
+// the schema return nil.
+func (d *ResourceData) Get(key string) interface{} {
+	v, _ := d.GetOk(key)
+	return v
+}
+
+// GetOk is like Get but also reports whether the key has any value set.
+func (d *ResourceData) GetOk(key string) (interface{}, bool) {
+	s, ok := d.schema[key]
+	if !ok {
+		return nil, false
+	}
+
+	switch s.Type {
+	case TypeMap:
+		m := d.getMap(key)
+		return m, len(m) > 0
+	case TypeList, TypeSet:
+		l := d.getList(key)
+		return l, len(l) > 0
+	default:
+		raw, ok := d.attrs[key]
+		if !ok {
+			return zeroValue(s.Type), false
+		}
+
+		v, err := coercePrimitive(s.Type, raw)
+		if err != nil {
+			return zeroValue(s.Type), false
+		}
+		return v, true
+	}
+}
+
+// Set stores value under key for the remainder of this operation, so later
+// Get calls (and the final State) see it.
+func (d *ResourceData) Set(key string, value interface{}) error {
+	s, ok := d.schema[key]
+	if !ok {
+		return fmt.Errorf("Invalid key: %s", key)
+	}
+
+	switch s.Type {
+	case TypeMap:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: value must be a map[string]interface{}", key)
+		}
+
+		d.clearPrefix(key + ".")
+		for k, v := range m {
+			d.attrs[key+"."+k] = fmt.Sprintf("%v", v)
+		}
+	case TypeList, TypeSet:
+		l, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: value must be a []interface{}", key)
+		}
+
+		d.clearPrefix(key + ".")
+		for i, v := range l {
+			d.attrs[fmt.Sprintf("%s.%d", key, i)] = fmt.Sprintf("%v", v)
+		}
+	default:
+		d.attrs[key] = fmt.Sprintf("%v", value)
+	}
+
+	return nil
+}
+
+// HasChange reports whether key's value differs between the original state
+// and its value as of this operation.
+func (d *ResourceData) HasChange(key string) bool {
+	o, n := d.GetChange(key)
+	return !reflect.DeepEqual(o, n)
+}
+
+// GetChange returns key's value before and after this operation.
+func (d *ResourceData) GetChange(key string) (interface{}, interface{}) {
+	s, ok := d.schema[key]
+	if !ok {
+		return nil, nil
+	}
+
+	return d.oldValue(key, s), d.Get(key)
+}
+
+// State assembles the final InstanceState for this resource as of this
+// operation, recomputing the element counts of any list, set or map
+// attributes so they stay consistent with whatever was actually written.
+func (d *ResourceData) State() *terraform.InstanceState {
+	if d.Id() == "" {
+		return nil
+	}
+
+	attrs := make(map[string]string, len(d.attrs)+1)
+	for k, v := range d.attrs {
+		attrs[k] = v
+	}
+	attrs["id"] = d.Id()
+
+	for k, s := range d.schema {
+		if s.Type != TypeList && s.Type != TypeSet && s.Type != TypeMap {
+			continue
+		}
+
+		prefix := k + "."
+		countKey := prefix + "#"
+		count := 0
+		for ak := range attrs {
+			if ak != countKey && strings.HasPrefix(ak, prefix) {
+				count++
+			}
+		}
+
+		if count > 0 {
+			attrs[countKey] = strconv.Itoa(count)
+		} else {
+			delete(attrs, countKey)
+		}
+	}
+
+	return &terraform.InstanceState{
+		ID:         d.Id(),
+		Attributes: attrs,
+		Meta:       d.meta,
+	}
+}
+
+func (d *ResourceData) getMap(key string) map[string]interface{} {
+	prefix := key + "."
+	result := map[string]interface{}{}
+	for k, v := range d.attrs {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if sub := k[len(prefix):]; sub != "#" {
+			result[sub] = v
+		}
+	}
+	return result
+}
+
+func (d *ResourceData) getList(key string) []interface{} {
+	prefix := key + "."
+	result := []interface{}{}
+	for k, v := range d.attrs {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if sub := k[len(prefix):]; sub != "#" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (d *ResourceData) clearPrefix(prefix string) {
+	for k := range d.attrs {
+		if strings.HasPrefix(k, prefix) {
+			delete(d.attrs, k)
+		}
+	}
+}
+
+func (d *ResourceData) oldValue(key string, s *Schema) interface{} {
+	attrs := map[string]string{}
+	if d.state != nil {
+		attrs = d.state.Attributes
+	}
+
+	switch s.Type {
+	case TypeMap, TypeList, TypeSet:
+		prefix := key + "."
+		result := map[string]interface{}{}
+		for k, v := range attrs {
+			if strings.HasPrefix(k, prefix) && k != prefix+"#" {
+				result[k[len(prefix):]] = v
+			}
+		}
+		if s.Type == TypeMap {
+			return result
+		}
+
+		list := make([]interface{}, 0, len(result))
+		for _, v := range result {
+			list = append(list, v)
+		}
+		return list
+	default:
+		raw, ok := attrs[key]
+		if !ok {
+			return zeroValue(s.Type)
+		}
+
+		v, err := coercePrimitive(s.Type, raw)
+		if err != nil {
+			return zeroValue(s.Type)
+		}
+		return v
+	}
+}
+
+func mergeAttributes(s *terraform.InstanceState, d *terraform.InstanceDiff) map[string]string {
+	attrs := map[string]string{}
+	if s != nil {
+		for k, v := range s.Attributes {
+			attrs[k] = v
+		}
+	}
+	if d != nil {
+		for k, ad := range d.Attributes {
+			if ad.NewRemoved {
+				delete(attrs, k)
+				continue
+			}
+			attrs[k] = ad.New
+		}
+	}
+	return attrs
+}
+
+func copyMeta(s *terraform.InstanceState) map[string]string {
+	if s == nil || s.Meta == nil {
+		return nil
+	}
+
+	m := make(map[string]string, len(s.Meta))
+	for k, v := range s.Meta {
+		m[k] = v
+	}
+	return m
+}
+
+func coercePrimitive(t ValueType, raw string) (interface{}, error) {
+	switch t {
+	case TypeBool:
+		return strconv.ParseBool(raw)
+	case TypeInt:
+		v, err := strconv.ParseInt(raw, 0, 0)
+		return int(v), err
+	case TypeFloat:
+		return strconv.ParseFloat(raw, 64)
+	case TypeString:
+		return raw, nil
+	default:
+		return raw, nil
+	}
+}
+
+func zeroValue(t ValueType) interface{} {
+	switch t {
+	case TypeBool:
+		return false
+	case TypeInt:
+		return 0
+	case TypeFloat:
+		return 0.0
+	case TypeString:
+		return ""
+	case TypeList, TypeSet:
+		return []interface{}{}
+	case TypeMap:
+		return map[string]interface{}{}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,248 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// CustomizeDiffFunc lets a Resource adjust its own pending diff before it is
+// shown to the operator: promoting a Computed field to a known value, or
+// forcing replacement based on the value of another field.
+type CustomizeDiffFunc func(*ResourceDiff, interface{}) error
+
+// ResourceDiff exposes the pending terraform.InstanceDiff for a
+// CustomizeDiff callback to inspect and adjust.
+type ResourceDiff struct {
+	schema schemaMap
+	state  *terraform.InstanceState
+	diff   *terraform.InstanceDiff
+}
+
+// Get returns key's value as it stands after the diff computed so far.
+func (d *ResourceDiff) Get(key string) interface{} {
+	data, _ := d.schema.Data(d.state, d.diff)
+	return data.Get(key)
+}
+
+// GetChange returns key's value before and after the diff computed so far.
+func (d *ResourceDiff) GetChange(key string) (interface{}, interface{}) {
+	data, _ := d.schema.Data(d.state, d.diff)
+	return data.GetChange(key)
+}
+
+// HasChange reports whether key differs between before and after the diff
+// computed so far.
+func (d *ResourceDiff) HasChange(key string) bool {
+	o, n := d.GetChange(key)
+	return !reflect.DeepEqual(o, n)
+}
+
+// SetNew overrides the planned new value for key, which must be Computed.
+// Use it to promote a Computed field to a known value once CustomizeDiff
+// can derive it from the rest of the configuration. This is the only point
+// at which the Computed-only restriction can be enforced, since which keys
+// a CustomizeDiff calls SetNew on isn't known until it runs.
+func (d *ResourceDiff) SetNew(key string, value interface{}) error {
+	s, ok := d.schema[key]
+	if !ok {
+		return fmt.Errorf("%s: not found in schema", key)
+	}
+	if !s.Computed {
+		return fmt.Errorf("%s: SetNew is only valid for Computed fields", key)
+	}
+
+	ad := d.attrDiff(key)
+	ad.New = fmt.Sprintf("%v", value)
+	ad.NewComputed = false
+	return nil
+}
+
+// SetNewComputed marks key, which must be Computed, as not knowable until
+// apply, overriding any value the config or state already gave it.
+func (d *ResourceDiff) SetNewComputed(key string) error {
+	s, ok := d.schema[key]
+	if !ok {
+		return fmt.Errorf("%s: not found in schema", key)
+	}
+	if !s.Computed {
+		return fmt.Errorf("%s: SetNewComputed is only valid for Computed fields", key)
+	}
+
+	ad := d.attrDiff(key)
+	ad.New = ""
+	ad.NewComputed = true
+	return nil
+}
+
+// ForceNew marks key, and any sub-attributes under it, as requiring the
+// resource to be replaced rather than updated in place.
+func (d *ResourceDiff) ForceNew(key string) error {
+	if _, ok := d.schema[key]; !ok {
+		return fmt.Errorf("%s: not found in schema", key)
+	}
+
+	prefix := key + "."
+	found := false
+	for k, ad := range d.diff.Attributes {
+		if k == key || strings.HasPrefix(k, prefix) {
+			ad.RequiresNew = true
+			found = true
+		}
+	}
+
+	if !found {
+		d.attrDiff(key).RequiresNew = true
+	}
+
+	return nil
+}
+
+// attrDiff returns the diff entry for key, creating one if CustomizeDiff
+// hasn't touched it yet. A freshly created entry is seeded with key's
+// current merged value in both Old and New, not left blank, so a caller
+// that only sets RequiresNew (ForceNew, on a field the config didn't
+// actually change) doesn't also blank the field out via mergeAttributes.
+func (d *ResourceDiff) attrDiff(key string) *terraform.ResourceAttrDiff {
+	if d.diff.Attributes == nil {
+		d.diff.Attributes = map[string]*terraform.ResourceAttrDiff{}
+	}
+
+	ad, ok := d.diff.Attributes[key]
+	if !ok {
+		current := mergeAttributes(d.state, d.diff)[key]
+		ad = &terraform.ResourceAttrDiff{Old: current, New: current}
+		d.diff.Attributes[key] = ad
+	}
+	return ad
+}
+
+// Diff compares s against the given configuration and produces the
+// InstanceDiff Apply would need to reconcile them, running CustomizeDiff (if
+// set) over the result before returning it.
+func (r *Resource) Diff(
+	s *terraform.InstanceState,
+	c *terraform.ResourceConfig,
+	meta interface{}) (*terraform.InstanceDiff, error) {
+	sm := schemaMap(r.Schema)
+
+	oldAttrs := map[string]string{}
+	if s != nil {
+		oldAttrs = s.Attributes
+	}
+
+	diff := &terraform.InstanceDiff{Attributes: map[string]*terraform.ResourceAttrDiff{}}
+
+	for k, attrSchema := range sm {
+		raw, ok := c.Get(k)
+		if !ok {
+			continue
+		}
+
+		switch attrSchema.Type {
+		case TypeMap:
+			m, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			diffMap(diff, oldAttrs, k, m, attrSchema.ForceNew)
+			continue
+		case TypeList, TypeSet:
+			l, ok := raw.([]interface{})
+			if !ok {
+				continue
+			}
+			diffList(diff, oldAttrs, k, l, attrSchema.ForceNew)
+			continue
+		}
+
+		old := oldAttrs[k]
+		new := fmt.Sprintf("%v", raw)
+		if old == new {
+			continue
+		}
+
+		diff.Attributes[k] = &terraform.ResourceAttrDiff{
+			Old:         old,
+			New:         new,
+			RequiresNew: attrSchema.ForceNew,
+		}
+	}
+
+	if r.CustomizeDiff != nil {
+		rd := &ResourceDiff{schema: sm, state: s, diff: diff}
+		if err := r.CustomizeDiff(rd, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(diff.Attributes) == 0 {
+		return nil, nil
+	}
+
+	return diff, nil
+}
+
+// diffMap flattens a TypeMap's changes into diff in the same "key.subkey"
+// shape ResourceData and ResourceDiff already read and write.
+func diffMap(
+	diff *terraform.InstanceDiff, oldAttrs map[string]string,
+	key string, m map[string]interface{}, forceNew bool) {
+	diffElems(diff, oldAttrs, key, m, forceNew)
+}
+
+// diffList flattens a TypeList/TypeSet's changes into diff using the same
+// "key.0", "key.1", ... scheme diffMap uses for map subkeys, so
+// ResourceData.getList sees the new elements too.
+func diffList(
+	diff *terraform.InstanceDiff, oldAttrs map[string]string,
+	key string, l []interface{}, forceNew bool) {
+	elems := make(map[string]interface{}, len(l))
+	for i, v := range l {
+		elems[strconv.Itoa(i)] = v
+	}
+	diffElems(diff, oldAttrs, key, elems, forceNew)
+}
+
+// diffElems does the actual "key.subkey" flattening shared by diffMap and
+// diffList: one diff entry per changed subkey, plus a NewRemoved entry for
+// any subkey that's gone from the new value entirely.
+func diffElems(
+	diff *terraform.InstanceDiff, oldAttrs map[string]string,
+	key string, elems map[string]interface{}, forceNew bool) {
+	prefix := key + "."
+	seen := map[string]bool{}
+
+	for ek, ev := range elems {
+		ak := prefix + ek
+		seen[ak] = true
+
+		old := oldAttrs[ak]
+		new := fmt.Sprintf("%v", ev)
+		if old == new {
+			continue
+		}
+
+		diff.Attributes[ak] = &terraform.ResourceAttrDiff{
+			Old:         old,
+			New:         new,
+			RequiresNew: forceNew,
+		}
+	}
+
+	for ak, old := range oldAttrs {
+		if ak == prefix+"#" || seen[ak] || !strings.HasPrefix(ak, prefix) {
+			continue
+		}
+
+		diff.Attributes[ak] = &terraform.ResourceAttrDiff{
+			Old:         old,
+			New:         "",
+			NewRemoved:  true,
+			RequiresNew: forceNew,
+		}
+	}
+}
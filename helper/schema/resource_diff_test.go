@@ -0,0 +1,202 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceDiff_customizeDiff_conditionalForceNew(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"tags": &Schema{
+				Type:     TypeMap,
+				Optional: true,
+			},
+			"recreate_on_tag_change": &Schema{
+				Type:     TypeBool,
+				Optional: true,
+			},
+		},
+		CustomizeDiff: func(d *ResourceDiff, meta interface{}) error {
+			if d.HasChange("tags") && d.Get("recreate_on_tag_change").(bool) {
+				return d.ForceNew("tags")
+			}
+			return nil
+		},
+	}
+
+	s := &terraform.InstanceState{
+		ID: "foo",
+		Attributes: map[string]string{
+			"tags.Name": "old",
+		},
+	}
+
+	cases := map[string]struct {
+		Recreate bool
+		Expect   bool
+	}{
+		"promotes to ForceNew when the flag is set":    {true, true},
+		"leaves the diff alone when the flag is unset": {false, false},
+	}
+
+	for name, tc := range cases {
+		rawConfig, err := config.NewRawConfig(map[string]interface{}{
+			"tags": map[string]interface{}{
+				"Name": "new",
+			},
+			"recreate_on_tag_change": tc.Recreate,
+		})
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		c := terraform.NewResourceConfig(rawConfig)
+
+		diff, err := r.Diff(s, c, nil)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+
+		ad := diff.Attributes["tags.Name"]
+		if ad == nil {
+			t.Fatalf("%s: expected a diff for tags.Name", name)
+		}
+		if ad.RequiresNew != tc.Expect {
+			t.Fatalf("%s: expected RequiresNew=%v, got %v", name, tc.Expect, ad.RequiresNew)
+		}
+	}
+}
+
+func TestResourceDiff_forceNewPreservesUnchangedValue(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"name": &Schema{
+				Type:     TypeString,
+				Optional: true,
+			},
+			"recreate": &Schema{
+				Type:     TypeBool,
+				Optional: true,
+			},
+		},
+		CustomizeDiff: func(d *ResourceDiff, meta interface{}) error {
+			if d.Get("recreate").(bool) {
+				return d.ForceNew("name")
+			}
+			return nil
+		},
+	}
+
+	s := &terraform.InstanceState{
+		ID: "foo",
+		Attributes: map[string]string{
+			"name": "my-resource",
+		},
+	}
+
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"name":     "my-resource",
+		"recreate": true,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c := terraform.NewResourceConfig(rawConfig)
+
+	diff, err := r.Diff(s, c, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ad := diff.Attributes["name"]
+	if ad == nil || !ad.RequiresNew {
+		t.Fatalf("expected name to be RequiresNew, got: %#v", ad)
+	}
+
+	merged := mergeAttributes(s, diff)
+	if merged["name"] != "my-resource" {
+		t.Fatalf("expected ForceNew on an unchanged field to preserve its value, got: %q", merged["name"])
+	}
+}
+
+func TestResourceDiff_listFlattening(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"names": &Schema{
+				Type:     TypeList,
+				Optional: true,
+				Elem:     &Schema{Type: TypeString},
+			},
+		},
+	}
+
+	s := &terraform.InstanceState{
+		ID: "foo",
+		Attributes: map[string]string{
+			"names.#": "1",
+			"names.0": "alice",
+		},
+	}
+
+	rawConfig, err := config.NewRawConfig(map[string]interface{}{
+		"names": []interface{}{"alice", "bob"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	c := terraform.NewResourceConfig(rawConfig)
+
+	diff, err := r.Diff(s, c, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if diff.Attributes["names"] != nil {
+		t.Fatalf("expected no bogus bare \"names\" diff entry, got: %#v", diff.Attributes["names"])
+	}
+	if ad := diff.Attributes["names.1"]; ad == nil || ad.New != "bob" {
+		t.Fatalf("expected names.1 to diff to \"bob\", got: %#v", ad)
+	}
+
+	data, err := schemaMap(r.Schema).Data(s, diff)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	got := data.Get("names").([]interface{})
+	if len(got) != 2 {
+		t.Fatalf("expected the diff to be visible through Get, got: %#v", got)
+	}
+}
+
+func TestResourceDiff_setNewRequiresComputed(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+			"bar": &Schema{
+				Type:     TypeInt,
+				Computed: true,
+			},
+		},
+	}
+
+	rd := &ResourceDiff{
+		schema: schemaMap(r.Schema),
+		diff:   &terraform.InstanceDiff{Attributes: map[string]*terraform.ResourceAttrDiff{}},
+	}
+
+	if err := rd.SetNew("foo", 1); err == nil {
+		t.Fatal("expected an error setting a non-Computed field")
+	}
+	if err := rd.SetNew("bar", 1); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if rd.diff.Attributes["bar"].New != "1" {
+		t.Fatalf("bad: %#v", rd.diff.Attributes["bar"])
+	}
+}
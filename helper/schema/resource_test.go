@@ -368,6 +368,33 @@ func TestResourceInternalValidate(t *testing.T) {
 			},
 			true,
 		},
+
+		// Versioned but not yet migrating: no DowngradeState required
+		{
+			&Resource{
+				SchemaVersion: 2,
+				Schema: map[string]*Schema{
+					"foo": &Schema{Type: TypeInt, Optional: true},
+				},
+			},
+			false,
+		},
+
+		// Versioned and actively migrating via MigrateState, but no
+		// DowngradeState to step a newer state back down
+		{
+			&Resource{
+				SchemaVersion: 2,
+				MigrateState: func(
+					v int, s *terraform.InstanceState, meta interface{}) (*terraform.InstanceState, error) {
+					return s, nil
+				},
+				Schema: map[string]*Schema{
+					"foo": &Schema{Type: TypeInt, Optional: true},
+				},
+			},
+			true,
+		},
 	}
 
 	for i, tc := range cases {
@@ -627,6 +654,137 @@ func TestResourceRefresh_needsMigration(t *testing.T) {
 	}
 }
 
+func TestResourceRefresh_needsDowngrade(t *testing.T) {
+	// The running provider is at SchemaVersion 1, but the state was written
+	// by a provider binary at SchemaVersion 2.
+	r := &Resource{
+		SchemaVersion: 1,
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	r.Read = func(d *ResourceData, m interface{}) error {
+		return d.Set("foo", d.Get("foo").(int)+1)
+	}
+
+	r.DowngradeState = func(
+		v int,
+		s *terraform.InstanceState,
+		meta interface{}) (*terraform.InstanceState, error) {
+		if v != 2 {
+			t.Fatalf("Expected fromVersion to be 2, got %d", v)
+		}
+
+		s.Attributes["foo"] = "12"
+		return s, nil
+	}
+
+	s := &terraform.InstanceState{
+		ID: "bar",
+		Attributes: map[string]string{
+			"foo": "99",
+		},
+		Meta: map[string]string{
+			"schema_version": "2",
+		},
+	}
+
+	actual, err := r.Refresh(s, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &terraform.InstanceState{
+		ID: "bar",
+		Attributes: map[string]string{
+			"id":  "bar",
+			"foo": "13",
+		},
+		Meta: map[string]string{
+			"schema_version": "1",
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n\nexpected: %#v\ngot: %#v", expected, actual)
+	}
+}
+
+func TestResourceRefresh_needsMultiStepDowngrade(t *testing.T) {
+	// State is three schema versions ahead of the running provider, so
+	// DowngradeState must be called once per version, from 3 down to 1.
+	r := &Resource{
+		SchemaVersion: 0,
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	r.Read = func(d *ResourceData, m interface{}) error {
+		return nil
+	}
+
+	var seen []int
+	r.DowngradeState = func(
+		v int,
+		s *terraform.InstanceState,
+		meta interface{}) (*terraform.InstanceState, error) {
+		seen = append(seen, v)
+		return s, nil
+	}
+
+	s := &terraform.InstanceState{
+		ID:         "bar",
+		Attributes: map[string]string{"foo": "1"},
+		Meta:       map[string]string{"schema_version": "3"},
+	}
+
+	if _, err := r.Refresh(s, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []int{3, 2, 1}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Fatalf("bad: %#v", seen)
+	}
+}
+
+func TestResourceRefresh_downgradeStateErr(t *testing.T) {
+	r := &Resource{
+		SchemaVersion: 1,
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	r.Read = func(d *ResourceData, m interface{}) error {
+		t.Fatal("Read should never be called!")
+		return nil
+	}
+
+	s := &terraform.InstanceState{
+		ID:         "bar",
+		Attributes: map[string]string{"foo": "1"},
+		Meta:       map[string]string{"schema_version": "2"},
+	}
+
+	// No DowngradeState defined, so refreshing a newer state should error
+	// rather than silently proceeding with a schema it doesn't match.
+	if _, err := r.Refresh(s, nil); err == nil {
+		t.Fatal("expected error, but got none!")
+	}
+}
+
 func TestResourceRefresh_noMigrationNeeded(t *testing.T) {
 	r := &Resource{
 		SchemaVersion: 2,
@@ -862,6 +1020,64 @@ func TestResourceValidate(t *testing.T) {
 	}
 }
 
+func TestResourceValidate_schemaValidateFunc(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+				ValidateFunc: func(v interface{}, k string) (ws []string, es []error) {
+					if v.(int) < 0 {
+						es = append(es, fmt.Errorf("%s must not be negative", k))
+					}
+					return
+				},
+			},
+		},
+	}
+
+	ranResourceLevel := false
+	r.ValidateFunc = func(g ResourceConfigGetter) (ws []string, es []error) {
+		ranResourceLevel = true
+		return
+	}
+
+	cases := map[string]struct {
+		Config         map[string]interface{}
+		ExpectedErrors []error
+	}{
+		"valid": {
+			Config: map[string]interface{}{
+				"foo": 3,
+			},
+		},
+		"invalid": {
+			Config: map[string]interface{}{
+				"foo": -1,
+			},
+			ExpectedErrors: []error{errors.New("foo must not be negative")},
+		},
+	}
+
+	for tn, tc := range cases {
+		ranResourceLevel = false
+
+		rawConfig, err := config.NewRawConfig(tc.Config)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tn, err)
+		}
+		c := terraform.NewResourceConfig(rawConfig)
+
+		_, errs := r.Validate(c)
+		if !reflect.DeepEqual(tc.ExpectedErrors, errs) {
+			t.Fatalf("%s: expected errors: %v, got: %v", tn, tc.ExpectedErrors, errs)
+		}
+		if !ranResourceLevel {
+			t.Fatalf("%s: resource-level ValidateFunc did not run", tn)
+		}
+	}
+}
+
 func TestResourceValidate_ComposeTestFunc(t *testing.T) {
 	errorFooGreaterBar := func(g ResourceConfigGetter) (ws []string, es []error) {
 		foo, fooKnown := g.Get("foo")
@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeoutsKey is the reserved InstanceState.Meta key under which a
+// resource's configured timeouts are persisted, so that Refresh still knows
+// what limits applied even if the state is reloaded by a process that
+// hasn't built the Resource's Timeouts from code yet.
+const TimeoutsKey = "e2bfb730-ecaa-11e6-8f88-34363bc7c4c0"
+
+// The timeout keys accepted by ResourceData.Timeout.
+const (
+	TimeoutCreate  = "create"
+	TimeoutRead    = "read"
+	TimeoutUpdate  = "update"
+	TimeoutDelete  = "delete"
+	TimeoutDefault = "default"
+)
+
+// ResourceTimeout lets a Resource declare how long Terraform should wait on
+// each CRUD operation before giving up. Any operation without its own
+// timeout falls back to Default.
+type ResourceTimeout struct {
+	Create time.Duration
+	Read   time.Duration
+	Update time.Duration
+	Delete time.Duration
+
+	Default time.Duration
+}
+
+// forKey returns the duration configured for the given operation, falling
+// back to Default when that operation has no duration of its own.
+func (t *ResourceTimeout) forKey(key string) time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	var d time.Duration
+	switch key {
+	case TimeoutCreate:
+		d = t.Create
+	case TimeoutRead:
+		d = t.Read
+	case TimeoutUpdate:
+		d = t.Update
+	case TimeoutDelete:
+		d = t.Delete
+	}
+
+	if d == 0 {
+		d = t.Default
+	}
+	return d
+}
+
+// validate rejects a ResourceTimeout with any negative duration, or with no
+// duration set at all, since either renders the field pointless.
+func (t *ResourceTimeout) validate() error {
+	if t == nil {
+		return nil
+	}
+
+	any := false
+	for key, d := range map[string]time.Duration{
+		TimeoutCreate:  t.Create,
+		TimeoutRead:    t.Read,
+		TimeoutUpdate:  t.Update,
+		TimeoutDelete:  t.Delete,
+		TimeoutDefault: t.Default,
+	} {
+		if d < 0 {
+			return fmt.Errorf("timeouts: %s must not be negative", key)
+		}
+		if d > 0 {
+			any = true
+		}
+	}
+
+	if !any {
+		return errors.New("timeouts: at least one of Create, Read, Update, Delete or Default must be set")
+	}
+
+	return nil
+}
+
+// encode flattens the configured (non-zero) timeouts to a JSON string
+// suitable for storage in InstanceState.Meta[TimeoutsKey].
+func (t *ResourceTimeout) encode() (string, error) {
+	if t == nil {
+		return "", nil
+	}
+
+	raw := map[string]time.Duration{}
+	for key, d := range map[string]time.Duration{
+		TimeoutCreate:  t.Create,
+		TimeoutRead:    t.Read,
+		TimeoutUpdate:  t.Update,
+		TimeoutDelete:  t.Delete,
+		TimeoutDefault: t.Default,
+	} {
+		if d > 0 {
+			raw[key] = d
+		}
+	}
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeTimeouts reverses encode, reading the timeouts persisted under
+// TimeoutsKey in an InstanceState's Meta.
+func decodeTimeouts(meta map[string]string) (*ResourceTimeout, error) {
+	raw, ok := meta[TimeoutsKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var durations map[string]time.Duration
+	if err := json.Unmarshal([]byte(raw), &durations); err != nil {
+		return nil, fmt.Errorf("error decoding timeouts: %s", err)
+	}
+
+	return &ResourceTimeout{
+		Create:  durations[TimeoutCreate],
+		Read:    durations[TimeoutRead],
+		Update:  durations[TimeoutUpdate],
+		Delete:  durations[TimeoutDelete],
+		Default: durations[TimeoutDefault],
+	}, nil
+}
@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestResourceTimeout_forKey(t *testing.T) {
+	timeouts := &ResourceTimeout{
+		Create:  10 * time.Minute,
+		Default: time.Minute,
+	}
+
+	if v := timeouts.forKey(TimeoutCreate); v != 10*time.Minute {
+		t.Fatalf("bad: %s", v)
+	}
+	if v := timeouts.forKey(TimeoutUpdate); v != time.Minute {
+		t.Fatalf("bad: %s", v)
+	}
+}
+
+func TestResourceTimeout_validate(t *testing.T) {
+	cases := []struct {
+		Timeouts *ResourceTimeout
+		Err      bool
+	}{
+		{nil, false},
+		{&ResourceTimeout{Create: time.Minute}, false},
+		{&ResourceTimeout{Create: -time.Minute}, true},
+		{&ResourceTimeout{}, true},
+	}
+
+	for i, tc := range cases {
+		err := tc.Timeouts.validate()
+		if err != nil != tc.Err {
+			t.Fatalf("%d: bad: %s", i, err)
+		}
+	}
+}
+
+func TestResourceApply_timeouts(t *testing.T) {
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+		Timeouts: &ResourceTimeout{
+			Create: 20 * time.Minute,
+		},
+	}
+
+	var got time.Duration
+	r.Create = func(d *ResourceData, m interface{}) error {
+		got = d.Timeout(TimeoutCreate)
+		d.SetId("foo")
+		return nil
+	}
+
+	d := &terraform.InstanceDiff{
+		Attributes: map[string]*terraform.ResourceAttrDiff{
+			"foo": &terraform.ResourceAttrDiff{New: "42"},
+		},
+	}
+
+	actual, err := r.Apply(nil, d, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if got != 20*time.Minute {
+		t.Fatalf("bad: %s", got)
+	}
+
+	if actual.Meta[TimeoutsKey] == "" {
+		t.Fatal("expected timeouts to be persisted in state Meta")
+	}
+}
+
+func TestResourceRefresh_timeoutsFromState(t *testing.T) {
+	// The Resource in code carries no Timeouts (as if the provider binary
+	// running Refresh lost that information, e.g. across a crash), so the
+	// limit Read sees must come from what was previously persisted in
+	// state.Meta instead.
+	encoded, err := (&ResourceTimeout{Read: 5 * time.Minute}).encode()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := &Resource{
+		Schema: map[string]*Schema{
+			"foo": &Schema{
+				Type:     TypeInt,
+				Optional: true,
+			},
+		},
+	}
+
+	var got time.Duration
+	r.Read = func(d *ResourceData, m interface{}) error {
+		got = d.Timeout(TimeoutRead)
+		return nil
+	}
+
+	s := &terraform.InstanceState{
+		ID:         "bar",
+		Attributes: map[string]string{"foo": "12"},
+		Meta:       map[string]string{TimeoutsKey: encoded},
+	}
+
+	actual, err := r.Refresh(s, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if got != 5*time.Minute {
+		t.Fatalf("expected the persisted Read timeout of 5m, got: %s", got)
+	}
+
+	if actual.Meta[TimeoutsKey] != encoded {
+		t.Fatalf("expected the persisted timeouts to round-trip, got: %q", actual.Meta[TimeoutsKey])
+	}
+}
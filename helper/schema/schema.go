@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ValueType is an enum of the underlying types a schema can represent.
+type ValueType int
+
+const (
+	TypeInvalid ValueType = iota
+	TypeBool
+	TypeInt
+	TypeFloat
+	TypeString
+	TypeList
+	TypeMap
+	TypeSet
+)
+
+// SchemaValidateFunc validates a single attribute's raw value. It is given
+// the value as the provider will see it (already coerced by config) and the
+// dotted config key it came from, and returns any number of warnings and
+// errors to attach to that key.
+type SchemaValidateFunc func(interface{}, string) ([]string, []error)
+
+// Schema describes the structure and constraints of a single attribute of a
+// Resource.
+type Schema struct {
+	Type     ValueType
+	Optional bool
+	Required bool
+	Computed bool
+	ForceNew bool
+
+	// Elem represents the element type for a TypeList, TypeSet or TypeMap,
+	// and is either a *Schema for primitive elements or a *Resource for
+	// nested blocks.
+	Elem interface{}
+
+	Default     interface{}
+	DefaultFunc func() (interface{}, error)
+	Description string
+
+	// ValidateFunc is run against this attribute's raw config value during
+	// Resource.Validate, before the resource-level ValidateFunc runs.
+	ValidateFunc SchemaValidateFunc
+}
+
+// schemaMap is a wrapper around map[string]*Schema that adds semantics
+// relevant to the framework, such as building ResourceData out of state and
+// diffs, and validating configuration against the schema.
+type schemaMap map[string]*Schema
+
+// Data creates a ResourceData for the given state and diff, merging them
+// according to this schema.
+func (m schemaMap) Data(
+	s *terraform.InstanceState,
+	d *terraform.InstanceDiff) (*ResourceData, error) {
+	return &ResourceData{
+		schema: m,
+		state:  s,
+		diff:   d,
+		attrs:  mergeAttributes(s, d),
+		meta:   copyMeta(s),
+	}, nil
+}
+
+// Validate checks the given resource configuration against the schema,
+// invoking each attribute's ValidateFunc (recursing into list, set and map
+// elements) and collecting their warnings and errors.
+func (m schemaMap) Validate(c *terraform.ResourceConfig) (ws []string, es []error) {
+	for k, s := range m {
+		raw, ok := c.Get(k)
+		if !ok {
+			if s.Required {
+				es = append(es, fmt.Errorf("%q is required", k))
+			}
+			continue
+		}
+
+		w, e := m.validateType(k, raw, s, c)
+		ws = append(ws, w...)
+		es = append(es, e...)
+	}
+
+	return ws, es
+}
+
+func (m schemaMap) validateType(
+	k string, raw interface{}, s *Schema, c *terraform.ResourceConfig) (ws []string, es []error) {
+	if raw == config.UnknownVariableValue {
+		return nil, nil
+	}
+
+	switch s.Type {
+	case TypeList, TypeSet:
+		return m.validateList(k, raw, s, c)
+	case TypeMap:
+		return m.validateMap(k, raw, s, c)
+	default:
+		return m.validatePrimitive(k, raw, s)
+	}
+}
+
+func (m schemaMap) validatePrimitive(
+	k string, raw interface{}, s *Schema) (ws []string, es []error) {
+	if s.ValidateFunc == nil {
+		return nil, nil
+	}
+
+	return s.ValidateFunc(raw, k)
+}
+
+func (m schemaMap) validateMap(
+	k string, raw interface{}, s *Schema, c *terraform.ResourceConfig) (ws []string, es []error) {
+	if s.ValidateFunc != nil {
+		w, e := s.ValidateFunc(raw, k)
+		ws = append(ws, w...)
+		es = append(es, e...)
+	}
+
+	elem, ok := s.Elem.(*Schema)
+	vm, mapOk := raw.(map[string]interface{})
+	if !ok || elem.ValidateFunc == nil || !mapOk {
+		return ws, es
+	}
+
+	for mk, mv := range vm {
+		w, e := elem.ValidateFunc(mv, fmt.Sprintf("%s.%s", k, mk))
+		ws = append(ws, w...)
+		es = append(es, e...)
+	}
+
+	return ws, es
+}
+
+func (m schemaMap) validateList(
+	k string, raw interface{}, s *Schema, c *terraform.ResourceConfig) (ws []string, es []error) {
+	if s.ValidateFunc != nil {
+		w, e := s.ValidateFunc(raw, k)
+		ws = append(ws, w...)
+		es = append(es, e...)
+	}
+
+	elem, ok := s.Elem.(*Schema)
+	rawList, listOk := raw.([]interface{})
+	if !ok || elem.ValidateFunc == nil || !listOk {
+		return ws, es
+	}
+
+	for i := range rawList {
+		key := fmt.Sprintf("%s.%d", k, i)
+		v, ok := c.Get(key)
+		if !ok {
+			continue
+		}
+
+		w, e := elem.ValidateFunc(v, key)
+		ws = append(ws, w...)
+		es = append(es, e...)
+	}
+
+	return ws, es
+}
+
+// InternalValidate sanity-checks the schema itself, independent of any
+// particular configuration.
+func (m schemaMap) InternalValidate(topSchemaMap schemaMap) error {
+	for k, v := range m {
+		if v.Type == TypeInvalid {
+			return fmt.Errorf("%s: Type must be specified", k)
+		}
+	}
+
+	return nil
+}